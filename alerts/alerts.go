@@ -0,0 +1,261 @@
+// Package alerts periodically polls a configured Prometheus server's HTTP
+// API for firing and pending alerts, so Pixiu can act as a lightweight
+// single-pane health surface next to the raw cluster_health metrics.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/pixiu/collector"
+	"github.com/pixiu/config"
+	"github.com/pixiu/global"
+)
+
+const subsystem = "alerts"
+
+// namedDesc pairs a *prometheus.Desc with the fqName/help/labels that went
+// into building it, so Descriptions() can report a collector.MetricDescription
+// for it without re-typing that information a second time.
+type namedDesc struct {
+	Name   string
+	Help   string
+	Labels []string
+	Desc   *prometheus.Desc
+}
+
+func newNamedDesc(name, help string, labels []string) namedDesc {
+	fqName := prometheus.BuildFQName(global.Namespace, subsystem, name)
+	return namedDesc{
+		Name:   fqName,
+		Help:   help,
+		Labels: labels,
+		Desc:   prometheus.NewDesc(fqName, help, labels, nil),
+	}
+}
+
+// Collector polls a Prometheus server's /api/v1/alerts and /api/v1/rules on
+// an interval, and exposes the result both as raw JSON (via Handler) and as
+// its own Prometheus gauges.
+type Collector struct {
+	client        promv1.API
+	pollInterval  time.Duration
+	labelMatchers []string
+
+	firing  namedDesc
+	pending namedDesc
+	up      namedDesc
+
+	mu         sync.RWMutex
+	alerts     []promv1.Alert
+	lastPollOK bool
+}
+
+// New builds a Collector that polls the Prometheus server at cfg.PrometheusURL.
+func New(cfg config.AlertsConfig) (*Collector, error) {
+	client, err := promapi.NewClient(promapi.Config{Address: cfg.PrometheusURL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Prometheus API client for %s: %w", cfg.PrometheusURL, err)
+	}
+
+	alertLabels := []string{"alertname", "severity"}
+
+	return &Collector{
+		client:        promv1.NewAPI(client),
+		pollInterval:  cfg.ParsedPollInterval,
+		labelMatchers: cfg.LabelMatchers,
+
+		firing: newNamedDesc("firing",
+			"Number of alerts currently firing on the configured Prometheus server, by alertname and severity.",
+			alertLabels),
+		pending: newNamedDesc("pending",
+			"Number of alerts currently pending on the configured Prometheus server, by alertname and severity.",
+			alertLabels),
+		up: newNamedDesc("up",
+			"Was the last poll of the configured Prometheus server successful.",
+			nil),
+	}, nil
+}
+
+// Run polls the Prometheus server on Collector's configured interval until
+// ctx is cancelled. It should be started in its own goroutine.
+func (c *Collector) Run(ctx context.Context) {
+	c.poll(ctx)
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.poll(ctx)
+		}
+	}
+}
+
+func (c *Collector) poll(ctx context.Context) {
+	alertsResult, err := c.client.Alerts(ctx)
+	if err != nil {
+		c.mu.Lock()
+		c.lastPollOK = false
+		c.mu.Unlock()
+		return
+	}
+
+	var ruleGroups []promv1.RuleGroup
+	if rulesResult, err := c.client.Rules(ctx); err == nil {
+		ruleGroups = rulesResult.Groups
+	}
+
+	all := dedupeAlerts(alertsResult.Alerts, ruleGroups)
+
+	if len(c.labelMatchers) > 0 {
+		all = filterAlerts(all, c.labelMatchers)
+	}
+
+	c.mu.Lock()
+	c.alerts = all
+	c.lastPollOK = true
+	c.mu.Unlock()
+}
+
+// dedupeAlerts merges flat (from /api/v1/alerts) with the alerts embedded in
+// ruleGroups (from /api/v1/rules), deduping by label fingerprint: an active
+// alert normally shows up in both places, so simply concatenating them
+// would double-count it.
+func dedupeAlerts(flat []promv1.Alert, ruleGroups []promv1.RuleGroup) []promv1.Alert {
+	seen := map[model.Fingerprint]promv1.Alert{}
+	for _, a := range flat {
+		seen[a.Labels.Fingerprint()] = a
+	}
+
+	for _, group := range ruleGroups {
+		for _, rule := range group.Rules {
+			alertingRule, ok := rule.(promv1.AlertingRule)
+			if !ok {
+				continue
+			}
+			for _, a := range alertingRule.Alerts {
+				seen[a.Labels.Fingerprint()] = *a
+			}
+		}
+	}
+
+	out := make([]promv1.Alert, 0, len(seen))
+	for _, a := range seen {
+		out = append(out, a)
+	}
+	return out
+}
+
+// filterAlerts keeps only alerts whose labels satisfy every "key=value"
+// matcher.
+func filterAlerts(alerts []promv1.Alert, matchers []string) []promv1.Alert {
+	var out []promv1.Alert
+	for _, a := range alerts {
+		if matchesAll(a.Labels, matchers) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func matchesAll(labels model.LabelSet, matchers []string) bool {
+	for _, m := range matchers {
+		k, v, ok := strings.Cut(m, "=")
+		if !ok {
+			continue
+		}
+		if string(labels[model.LabelName(k)]) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.firing.Desc
+	ch <- c.pending.Desc
+	ch <- c.up.Desc
+}
+
+// Descriptions returns a MetricDescription for every metric this collector
+// can emit, read straight off this instance's own fields, so it can take
+// part in collector.Descriptors alongside the *Health collectors.
+func (c *Collector) Descriptions() []collector.MetricDescription {
+	return []collector.MetricDescription{
+		{Name: c.firing.Name, Help: c.firing.Help, Type: "gauge", Labels: c.firing.Labels},
+		{Name: c.pending.Name, Help: c.pending.Help, Type: "gauge", Labels: c.pending.Labels},
+		{Name: c.up.Name, Help: c.up.Help, Type: "gauge", Labels: c.up.Labels},
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	alerts := c.alerts
+	lastPollOK := c.lastPollOK
+	c.mu.RUnlock()
+
+	upValue := 0.0
+	if lastPollOK {
+		upValue = 1.0
+	}
+	ch <- prometheus.MustNewConstMetric(c.up.Desc, prometheus.GaugeValue, upValue)
+
+	type key struct {
+		alertname string
+		severity  string
+	}
+
+	firing := map[key]float64{}
+	pending := map[key]float64{}
+
+	for _, a := range alerts {
+		k := key{
+			alertname: string(a.Labels["alertname"]),
+			severity:  string(a.Labels["severity"]),
+		}
+
+		switch a.State {
+		case promv1.AlertStateFiring:
+			firing[k]++
+		case promv1.AlertStatePending:
+			pending[k]++
+		}
+	}
+
+	for k, count := range firing {
+		ch <- prometheus.MustNewConstMetric(c.firing.Desc, prometheus.GaugeValue, count, k.alertname, k.severity)
+	}
+	for k, count := range pending {
+		ch <- prometheus.MustNewConstMetric(c.pending.Desc, prometheus.GaugeValue, count, k.alertname, k.severity)
+	}
+}
+
+// Handler serves the most recently polled alerts as raw JSON.
+func (c *Collector) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		alerts := c.alerts
+		c.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(alerts); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
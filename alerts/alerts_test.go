@@ -0,0 +1,105 @@
+package alerts
+
+import (
+	"sort"
+	"testing"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+func alertNames(alerts []promv1.Alert) []string {
+	names := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		names = append(names, string(a.Labels["alertname"]))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDedupeAlerts(t *testing.T) {
+	diskFull := promv1.Alert{Labels: model.LabelSet{"alertname": "DiskFull", "severity": "critical"}}
+	highLatency := promv1.Alert{Labels: model.LabelSet{"alertname": "HighLatency", "severity": "warning"}}
+
+	ruleGroups := []promv1.RuleGroup{
+		{
+			Rules: promv1.Rules{
+				// Same alert as in the flat list: must collapse to one.
+				promv1.AlertingRule{Alerts: []*promv1.Alert{&diskFull}},
+				// Only present via rules: must still show up.
+				promv1.AlertingRule{Alerts: []*promv1.Alert{&highLatency}},
+				// A recording rule, not an alerting rule: must be ignored.
+				promv1.RecordingRule{},
+			},
+		},
+	}
+
+	got := dedupeAlerts([]promv1.Alert{diskFull}, ruleGroups)
+
+	want := []string{"DiskFull", "HighLatency"}
+	if names := alertNames(got); !equalStrings(names, want) {
+		t.Errorf("dedupeAlerts() alert names = %v, want %v", names, want)
+	}
+}
+
+func TestDedupeAlertsNoRules(t *testing.T) {
+	diskFull := promv1.Alert{Labels: model.LabelSet{"alertname": "DiskFull"}}
+
+	got := dedupeAlerts([]promv1.Alert{diskFull}, nil)
+
+	want := []string{"DiskFull"}
+	if names := alertNames(got); !equalStrings(names, want) {
+		t.Errorf("dedupeAlerts() alert names = %v, want %v", names, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFilterAlerts(t *testing.T) {
+	alerts := []promv1.Alert{
+		{Labels: model.LabelSet{"alertname": "DiskFull", "severity": "critical"}},
+		{Labels: model.LabelSet{"alertname": "HighLatency", "severity": "warning"}},
+	}
+
+	got := filterAlerts(alerts, []string{"severity=critical"})
+
+	want := []string{"DiskFull"}
+	if names := alertNames(got); !equalStrings(names, want) {
+		t.Errorf("filterAlerts() alert names = %v, want %v", names, want)
+	}
+}
+
+func TestMatchesAll(t *testing.T) {
+	labels := model.LabelSet{"alertname": "DiskFull", "severity": "critical"}
+
+	tests := []struct {
+		name     string
+		matchers []string
+		want     bool
+	}{
+		{name: "no matchers", matchers: nil, want: true},
+		{name: "single matching matcher", matchers: []string{"severity=critical"}, want: true},
+		{name: "all matchers must match", matchers: []string{"severity=critical", "alertname=DiskFull"}, want: true},
+		{name: "one mismatching matcher fails", matchers: []string{"severity=warning"}, want: false},
+		{name: "missing label fails", matchers: []string{"team=storage"}, want: false},
+		{name: "malformed matcher without = is ignored", matchers: []string{"not-a-matcher"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesAll(labels, tt.matchers); got != tt.want {
+				t.Errorf("matchesAll(%v, %v) = %v, want %v", labels, tt.matchers, got, tt.want)
+			}
+		})
+	}
+}
@@ -11,9 +11,11 @@ import (
 
 	"path"
 
-	"github.com/pixiu/global"
+	"github.com/pixiu/config"
+	"github.com/pixiu/httpclient"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -23,10 +25,13 @@ const (
 
 var (
 	colors                     = []string{"green", "yellow", "red"}
-	defaultClusterHealthLabels = []string{"cluster"}
+	defaultClusterHealthLabels = []string{"cluster", "target"}
 )
 
-type clusterHealthResponse struct {
+// ClusterHealthResponse is the decoded body of Elasticsearch's
+// /_cluster/health endpoint. It's exported so a custom Interpreter can be
+// implemented outside this package.
+type ClusterHealthResponse struct {
 	ClusterName                 string  `json:"cluster_name"`
 	Status                      string  `json:"status"`
 	TimedOut                    bool    `json:"timed_out"`
@@ -46,187 +51,153 @@ type clusterHealthResponse struct {
 
 type clusterHealthMetric struct {
 	Type  prometheus.ValueType
-	Desc  *prometheus.Desc
-	Value func(clusterHealth clusterHealthResponse) float64
+	Value func(clusterHealth ClusterHealthResponse) float64
+	namedDesc
+}
+
+// newClusterHealthMetric builds a gauge-valued clusterHealthMetric backed by
+// a namedDesc.
+func newClusterHealthMetric(name, help string, value func(ClusterHealthResponse) float64) *clusterHealthMetric {
+	return &clusterHealthMetric{
+		Type:      prometheus.GaugeValue,
+		Value:     value,
+		namedDesc: newNamedDesc(clusterHealthSubsystem, name, help, defaultClusterHealthLabels),
+	}
 }
 
 type clusterHealthStatusMetric struct {
-	Type   prometheus.ValueType
-	Desc   *prometheus.Desc
-	Value  func(clusterHealth clusterHealthResponse, color string) float64
-	Labels func(clusterName, color string) []string
+	Type  prometheus.ValueType
+	Value func(clusterHealth ClusterHealthResponse, color string) float64
+	namedDesc
 }
 
-type ClusterHealth struct {
-	logger log.Logger
-	client *http.Client
-	url    *url.URL
+// clusterHealthMetrics, clusterHealthStatus and clusterHealthStatusInterp
+// are built once at package-init time and shared by every ClusterHealth
+// instance: their Desc/Value shape doesn't depend on which target or client
+// they're scraping, only the label values passed to MustNewConstMetric do.
+var (
+	clusterHealthMetrics = []*clusterHealthMetric{
+		newClusterHealthMetric("active_primary_shards",
+			"The number of primary shards in your cluster. This is an aggregate total across all indices.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.ActivePrimaryShards)
+			}),
+		newClusterHealthMetric("active_shards",
+			"Aggregate total of all shards across all indices, which includes replica shards.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.ActiveShards)
+			}),
+		newClusterHealthMetric("delayed_unassigned_shards",
+			"Shards delayed to reduce reallocation overhead",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.DelayedUnassignedShards)
+			}),
+		newClusterHealthMetric("initializing_shards",
+			"Count of shards that are being freshly created.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.InitializingShards)
+			}),
+		newClusterHealthMetric("number_of_data_nodes",
+			"Number of data nodes in the cluster.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.NumberOfDataNodes)
+			}),
+		newClusterHealthMetric("number_of_in_flight_fetch",
+			"The number of ongoing shard info requests.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.NumberOfInFlightFetch)
+			}),
+		newClusterHealthMetric("task_max_waiting_in_queue_millis",
+			"Tasks max time waiting in queue.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.TaskMaxWaitingInQueueMillis)
+			}),
+		newClusterHealthMetric("number_of_nodes",
+			"Number of nodes in the cluster.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.NumberOfNodes)
+			}),
+		newClusterHealthMetric("number_of_pending_tasks",
+			"Cluster level changes which have not yet been executed",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.NumberOfPendingTasks)
+			}),
+		newClusterHealthMetric("relocating_shards",
+			"The number of shards that are currently moving from one node to another node.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.RelocatingShards)
+			}),
+		newClusterHealthMetric("unassigned_shards",
+			"The number of shards that exist in the cluster state, but cannot be found in the cluster itself.",
+			func(clusterHealth ClusterHealthResponse) float64 {
+				return float64(clusterHealth.UnassignedShards)
+			}),
+	}
+
+	clusterHealthStatusLabels = append(append([]string{}, defaultClusterHealthLabels...), "color")
 
-	up                prometheus.Gauge
-	totalScrapes      prometheus.Counter
-	jsonParseFailures prometheus.Counter
+	clusterHealthStatus = &clusterHealthStatusMetric{
+		Type: prometheus.GaugeValue,
+		Value: func(clusterHealth ClusterHealthResponse, color string) float64 {
+			if clusterHealth.Status == color {
+				return 1
+			}
+			return 0
+		},
+		namedDesc: newNamedDesc(clusterHealthSubsystem, "status",
+			"Whether all primary and replica shards are allocated.", clusterHealthStatusLabels),
+	}
 
-	metrics      []*clusterHealthMetric
-	statusMetric *clusterHealthStatusMetric
+	clusterHealthStatusInterp = newNamedDesc(clusterHealthSubsystem, "status_interp",
+		"Interpreted cluster health status: 0=ok (green), 1=soft_warn, 2=critical_warn, 3=err (red).",
+		defaultClusterHealthLabels)
+)
+
+type ClusterHealth struct {
+	logger      log.Logger
+	client      *http.Client
+	url         *url.URL
+	target      string
+	interpreter Interpreter
+	limiter     scrapeLimiter
+
+	bookkeeping bookkeepingMetrics
+
+	metrics          []*clusterHealthMetric
+	statusMetric     *clusterHealthStatusMetric
+	statusInterpDesc namedDesc
 }
 
-func NewClusterHealth(logger log.Logger, client *http.Client, url *url.URL) *ClusterHealth {
+// NewClusterHealth builds a ClusterHealth collector for a single upstream
+// cluster. target is a stable, user-chosen identifier for this upstream
+// (distinct from the cluster's own cluster_name) and is emitted on every
+// metric so that two clusters sharing a cluster_name remain distinguishable
+// once Pixiu scrapes more than one of them. If interpreter is nil, the
+// default threshold-based Interpreter is used. limiter bounds concurrent
+// upstream HTTP scrapes across every collector sharing it; a nil limiter
+// applies no bound.
+func NewClusterHealth(logger log.Logger, client *http.Client, url *url.URL, target string, interpreter Interpreter, limiter scrapeLimiter) *ClusterHealth {
+	if interpreter == nil {
+		interpreter = NewThresholdInterpreter(config.DefaultInterpreterThresholds)
+	}
 
 	return &ClusterHealth{
-		logger: logger,
-		client: client,
-		url:    url,
-
-		up: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "up"),
-			Help: "Was the last scrape of the Pixiu cluster health  successful.",
-		}),
-		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
-			Name: prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "total_scrapes"),
-			Help: "Current total Pixiu cluster health scrapes.",
-		}),
-		jsonParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
-			Name: prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "json_parse_failures"),
-			Help: "Number of errors while parsing JSON.",
-		}),
-
-		metrics: []*clusterHealthMetric{
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "active_primary_shards"),
-					"The number of primary shards in your cluster. This is an aggregate total across all indices.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.ActivePrimaryShards)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "active_shards"),
-					"Aggregate total of all shards across all indices, which includes replica shards.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.ActiveShards)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "delayed_unassigned_shards"),
-					"Shards delayed to reduce reallocation overhead",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.DelayedUnassignedShards)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "initializing_shards"),
-					"Count of shards that are being freshly created.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.InitializingShards)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "number_of_data_nodes"),
-					"Number of data nodes in the cluster.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.NumberOfDataNodes)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "number_of_in_flight_fetch"),
-					"The number of ongoing shard info requests.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.NumberOfInFlightFetch)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "task_max_waiting_in_queue_millis"),
-					"Tasks max time waiting in queue.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.TaskMaxWaitingInQueueMillis)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "number_of_nodes"),
-					"Number of nodes in the cluster.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.NumberOfNodes)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "number_of_pending_tasks"),
-					"Cluster level changes which have not yet been executed",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.NumberOfPendingTasks)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "relocating_shards"),
-					"The number of shards that are currently moving from one node to another node.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.RelocatingShards)
-				},
-			},
-			{
-				Type: prometheus.GaugeValue,
-				Desc: prometheus.NewDesc(
-					prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "unassigned_shards"),
-					"The number of shards that exist in the cluster state, but cannot be found in the cluster itself.",
-					defaultClusterHealthLabels, nil,
-				),
-				Value: func(clusterHealth clusterHealthResponse) float64 {
-					return float64(clusterHealth.UnassignedShards)
-				},
-			},
-		},
-		statusMetric: &clusterHealthStatusMetric{
-			Type: prometheus.GaugeValue,
-			Desc: prometheus.NewDesc(
-				prometheus.BuildFQName(global.Namespace, clusterHealthSubsystem, "status"),
-				"Whether all primary and replica shards are allocated.",
-				[]string{"cluster", "color"}, nil,
-			),
-			Value: func(clusterHealth clusterHealthResponse, color string) float64 {
-				if clusterHealth.Status == color {
-					return 1
-				}
-				return 0
-			},
-		},
+		logger:      logger,
+		client:      client,
+		url:         url,
+		target:      target,
+		interpreter: interpreter,
+		limiter:     limiter,
+
+		bookkeeping: newBookkeepingMetrics(clusterHealthSubsystem, target,
+			"Was the last scrape of the Pixiu cluster health successful.",
+			"Current total Pixiu cluster health scrapes.",
+			"Number of errors while parsing JSON.",
+		),
+
+		metrics:          clusterHealthMetrics,
+		statusMetric:     clusterHealthStatus,
+		statusInterpDesc: clusterHealthStatusInterp,
 	}
 }
 
@@ -236,26 +207,40 @@ func (c *ClusterHealth) Describe(ch chan<- *prometheus.Desc) {
 		ch <- metric.Desc
 	}
 	ch <- c.statusMetric.Desc
+	ch <- c.statusInterpDesc.Desc
 
-	ch <- c.up.Desc()
-	ch <- c.totalScrapes.Desc()
-	ch <- c.jsonParseFailures.Desc()
+	ch <- c.bookkeeping.Up.Desc()
+	ch <- c.bookkeeping.TotalScrapes.Desc()
+	ch <- c.bookkeeping.JSONParseFailures.Desc()
+}
+
+// Descriptions returns a MetricDescription for every metric this collector
+// can emit, read straight off this instance's own fields.
+func (c *ClusterHealth) Descriptions() []MetricDescription {
+	out := make([]MetricDescription, 0, len(c.metrics)+5)
+	for _, metric := range c.metrics {
+		out = append(out, metric.description(gaugeType))
+	}
+	out = append(out, c.statusMetric.description(gaugeType))
+	out = append(out, c.statusInterpDesc.description(gaugeType))
+	out = append(out, c.bookkeeping.Descriptions()...)
+	return out
 }
 
 // Collect collects ClusterHealth metrics.
 func (c *ClusterHealth) Collect(ch chan<- prometheus.Metric) {
 	var err error
-	c.totalScrapes.Inc()
+	c.bookkeeping.TotalScrapes.Inc()
 
 	defer func() {
-		ch <- c.up
-		ch <- c.totalScrapes
-		ch <- c.jsonParseFailures
+		ch <- c.bookkeeping.Up
+		ch <- c.bookkeeping.TotalScrapes
+		ch <- c.bookkeeping.JSONParseFailures
 	}()
 
 	clusterHealthResp, err := c.fetchAndDecodeClusterHealth()
 	if err != nil {
-		c.up.Set(0)
+		c.bookkeeping.Up.Set(0)
 		_ = c.logger.Output(
 			2,
 			"msg:"+"failed to fetch and decode cluster health"+"err:"+err.Error(),
@@ -263,14 +248,14 @@ func (c *ClusterHealth) Collect(ch chan<- prometheus.Metric) {
 		return
 	}
 
-	c.up.Set(1)
+	c.bookkeeping.Up.Set(1)
 
 	for _, metric := range c.metrics {
 		ch <- prometheus.MustNewConstMetric(
 			metric.Desc,
 			metric.Type,
 			metric.Value(clusterHealthResp),
-			clusterHealthResp.ClusterName,
+			clusterHealthResp.ClusterName, c.target,
 		)
 	}
 
@@ -279,16 +264,27 @@ func (c *ClusterHealth) Collect(ch chan<- prometheus.Metric) {
 			c.statusMetric.Desc,
 			c.statusMetric.Type,
 			c.statusMetric.Value(clusterHealthResp, color),
-			clusterHealthResp.ClusterName, color,
+			clusterHealthResp.ClusterName, c.target, color,
 		)
 	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.statusInterpDesc.Desc,
+		prometheus.GaugeValue,
+		float64(c.interpreter.Interpret(clusterHealthResp)),
+		clusterHealthResp.ClusterName, c.target,
+	)
 }
 
-func (c *ClusterHealth) fetchAndDecodeClusterHealth() (clusterHealthResponse, error) {
-	var chr clusterHealthResponse
+func (c *ClusterHealth) fetchAndDecodeClusterHealth() (ClusterHealthResponse, error) {
+	var chr ClusterHealthResponse
 
 	u := *c.url
 	u.Path = path.Join(u.Path, "/_cluster/health")
+
+	c.limiter.acquire()
+	defer c.limiter.release()
+
 	res, err := c.client.Get(u.String())
 	if err != nil {
 		return chr, fmt.Errorf("failed to get cluster health from %s://%s:%s%s: %s",
@@ -311,26 +307,119 @@ func (c *ClusterHealth) fetchAndDecodeClusterHealth() (clusterHealthResponse, er
 
 	bts, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		c.jsonParseFailures.Inc()
+		c.bookkeeping.JSONParseFailures.Inc()
 		return chr, err
 	}
 
 	if err := json.Unmarshal(bts, &chr); err != nil {
-		c.jsonParseFailures.Inc()
+		c.bookkeeping.JSONParseFailures.Inc()
 		return chr, err
 	}
 
 	return chr, nil
 }
 
-func NewHandler(logger log.Logger, client *http.Client, url *url.URL) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+// maxConcurrentScrapes bounds how many upstream HTTP scrapes (across every
+// target and collector) are in flight at once for a single incoming
+// request, so one slow upstream cluster can't starve the others of
+// connections. It's enforced by the scrapeLimiter handed to each collector
+// built for that request, not by the registration errgroup below, which
+// only parallelizes the cheap Register(...) calls themselves.
+const maxConcurrentScrapes = 8
+
+// scrapeTarget is a configured target together with the client Pixiu
+// reaches it through.
+type scrapeTarget struct {
+	target config.Target
+	client *http.Client
+	prober *httpclient.Prober
+}
+
+// newScrapeTargets builds the auth- and TLS-aware http.Client for every
+// configured target so NewHandler doesn't have to special-case secured
+// upstreams.
+func newScrapeTargets(targets []config.Target) ([]scrapeTarget, error) {
+	scrapeTargets := make([]scrapeTarget, 0, len(targets))
+
+	for _, t := range targets {
+		transport, err := httpclient.New(t, httpclient.DefaultBearerTokenReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", t.Name, err)
+		}
+
+		prober := httpclient.NewProber(transport, t.Name)
+		scrapeTargets = append(scrapeTargets, scrapeTarget{
+			target: t,
+			client: &http.Client{Transport: prober},
+			prober: prober,
+		})
+	}
+
+	return scrapeTargets, nil
+}
+
+// NewHandler builds an http.HandlerFunc that scrapes every configured target
+// concurrently and merges their metrics into a single exposition, each
+// tagged with its own target label, plus a companion probe handler exposing
+// the transport health (TLS cert expiry, last handshake error) of every
+// target independently of the cluster health scrape.
+func NewHandler(logger log.Logger, targets []config.Target, thresholds config.InterpreterThresholds) (http.HandlerFunc, http.HandlerFunc, error) {
+	interpreter := NewThresholdInterpreter(thresholds)
+
+	scrapeTargets, err := newScrapeTargets(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	probers := make([]*httpclient.Prober, 0, len(scrapeTargets))
+	for _, st := range scrapeTargets {
+		probers = append(probers, st.prober)
+	}
+
+	metricsHandler := func(w http.ResponseWriter, r *http.Request) {
+		collect := map[string]bool{}
+		for _, c := range r.URL.Query()["collect[]"] {
+			collect[c] = true
+		}
+
 		registry := prometheus.NewRegistry()
-		registry.MustRegister(NewClusterHealth(logger, client, url))
+		limiter := newScrapeLimiter(maxConcurrentScrapes)
+
+		g, _ := errgroup.WithContext(r.Context())
+
+		for _, st := range scrapeTargets {
+			st := st
+			g.Go(func() error {
+				if err := registry.Register(NewClusterHealth(logger, st.client, st.target.ParsedURL, st.target.Name, interpreter, limiter)); err != nil {
+					return err
+				}
+				if collect["indices"] {
+					if err := registry.Register(NewIndexHealth(logger, st.client, st.target.ParsedURL, st.target.Name, limiter)); err != nil {
+						return err
+					}
+				}
+				if collect["shards"] {
+					if err := registry.Register(NewShardHealth(logger, st.client, st.target.ParsedURL, st.target.Name, limiter)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			_ = logger.Output(
+				2,
+				"msg:"+"failed to register cluster health collector"+"err:"+err.Error(),
+			)
+		}
+
 		gatherers := prometheus.Gatherers{
 			registry,
 		}
 		h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
 		h.ServeHTTP(w, r)
 	}
+
+	return metricsHandler, httpclient.NewProbeHandler(probers...), nil
 }
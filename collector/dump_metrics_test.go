@@ -0,0 +1,64 @@
+//go:build dump_metrics
+
+// This file lives in package collector_test, not collector: it pulls in
+// alerts, which itself imports collector, and an internal test file can't
+// do that without an import cycle.
+package collector_test
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/pixiu/alerts"
+	"github.com/pixiu/collector"
+	"github.com/pixiu/config"
+	"github.com/pixiu/httpclient"
+)
+
+// TestDumpMetrics builds one instance of every collector this repo ships
+// (mirroring NewHandler's wiring) and writes a JSON manifest of every metric
+// name, help text, type and labels Pixiu can emit. Run via
+// `make dump-metrics`; CI can diff the output against a checked-in copy to
+// catch accidental metric renames.
+func TestDumpMetrics(t *testing.T) {
+	var logger log.Logger
+	dumpURL, err := url.Parse("http://example.invalid")
+	if err != nil {
+		t.Fatalf("failed to parse placeholder target URL: %v", err)
+	}
+
+	alertsCollector, err := alerts.New(config.AlertsConfig{PrometheusURL: "http://example.invalid"})
+	if err != nil {
+		t.Fatalf("failed to build alerts collector: %v", err)
+	}
+
+	descriptions := collector.Descriptors(
+		collector.NewClusterHealth(logger, http.DefaultClient, dumpURL, "dump-metrics", nil, nil),
+		collector.NewIndexHealth(logger, http.DefaultClient, dumpURL, "dump-metrics", nil),
+		collector.NewShardHealth(logger, http.DefaultClient, dumpURL, "dump-metrics", nil),
+		alertsCollector,
+	)
+
+	prober := httpclient.NewProber(http.DefaultTransport, "dump-metrics")
+	for _, d := range prober.Descriptions() {
+		descriptions = append(descriptions, collector.MetricDescription{
+			Name:        d.Name,
+			Help:        d.Help,
+			Type:        "gauge",
+			ConstLabels: d.ConstLabels,
+		})
+	}
+
+	b, err := json.MarshalIndent(descriptions, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal metric descriptions: %v", err)
+	}
+
+	if err := os.WriteFile("metrics.json", b, 0o644); err != nil {
+		t.Fatalf("failed to write metrics.json: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const indexHealthSubsystem = "index_health_subsystem"
+
+var defaultIndexHealthLabels = []string{"cluster", "target", "index"}
+
+type indexHealthResponse struct {
+	Status              string `json:"status"`
+	NumberOfShards      int    `json:"number_of_shards"`
+	NumberOfReplicas    int    `json:"number_of_replicas"`
+	ActivePrimaryShards int    `json:"active_primary_shards"`
+	ActiveShards        int    `json:"active_shards"`
+	RelocatingShards    int    `json:"relocating_shards"`
+	InitializingShards  int    `json:"initializing_shards"`
+	UnassignedShards    int    `json:"unassigned_shards"`
+}
+
+type clusterIndicesHealthResponse struct {
+	ClusterName string                         `json:"cluster_name"`
+	Indices     map[string]indexHealthResponse `json:"indices"`
+}
+
+type indexHealthMetric struct {
+	Type  prometheus.ValueType
+	Value func(indexHealth indexHealthResponse) float64
+	namedDesc
+}
+
+// newIndexHealthMetric builds a gauge-valued indexHealthMetric backed by a
+// namedDesc.
+func newIndexHealthMetric(name, help string, value func(indexHealthResponse) float64) *indexHealthMetric {
+	return &indexHealthMetric{
+		Type:      prometheus.GaugeValue,
+		Value:     value,
+		namedDesc: newNamedDesc(indexHealthSubsystem, name, help, defaultIndexHealthLabels),
+	}
+}
+
+// indexHealthMetrics and indexHealthStatus are built once at package-init
+// time and shared by every IndexHealth instance, mirroring
+// clusterHealthMetrics.
+var (
+	indexHealthMetrics = []*indexHealthMetric{
+		newIndexHealthMetric("number_of_shards",
+			"Number of primary shards configured for this index.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.NumberOfShards)
+			}),
+		newIndexHealthMetric("number_of_replicas",
+			"Number of replicas configured for this index.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.NumberOfReplicas)
+			}),
+		newIndexHealthMetric("active_primary_shards",
+			"The number of active primary shards for this index.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.ActivePrimaryShards)
+			}),
+		newIndexHealthMetric("active_shards",
+			"Aggregate total of all shards for this index, including replica shards.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.ActiveShards)
+			}),
+		newIndexHealthMetric("relocating_shards",
+			"The number of shards of this index currently moving from one node to another node.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.RelocatingShards)
+			}),
+		newIndexHealthMetric("initializing_shards",
+			"Count of shards of this index that are being freshly created.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.InitializingShards)
+			}),
+		newIndexHealthMetric("unassigned_shards",
+			"The number of shards of this index that exist in the cluster state, but cannot be found in the cluster itself.",
+			func(indexHealth indexHealthResponse) float64 {
+				return float64(indexHealth.UnassignedShards)
+			}),
+	}
+
+	indexHealthStatusLabels = append(append([]string{}, defaultIndexHealthLabels...), "color")
+
+	indexHealthStatus = newNamedDesc(indexHealthSubsystem, "status",
+		"Whether all primary and replica shards of this index are allocated.", indexHealthStatusLabels)
+)
+
+// IndexHealth collects per-index health by calling
+// /_cluster/health?level=indices, so a yellow/red cluster can be narrowed
+// down to the specific index responsible instead of only seeing the
+// cluster-wide aggregate.
+type IndexHealth struct {
+	logger  log.Logger
+	client  *http.Client
+	url     *url.URL
+	target  string
+	limiter scrapeLimiter
+
+	bookkeeping bookkeepingMetrics
+
+	metrics    []*indexHealthMetric
+	statusDesc namedDesc
+}
+
+// NewIndexHealth builds an IndexHealth collector for a single upstream
+// cluster. limiter bounds concurrent upstream HTTP scrapes across every
+// collector sharing it; a nil limiter applies no bound.
+func NewIndexHealth(logger log.Logger, client *http.Client, url *url.URL, target string, limiter scrapeLimiter) *IndexHealth {
+	return &IndexHealth{
+		logger:  logger,
+		client:  client,
+		url:     url,
+		target:  target,
+		limiter: limiter,
+
+		bookkeeping: newBookkeepingMetrics(indexHealthSubsystem, target,
+			"Was the last scrape of the Pixiu index health successful.",
+			"Current total Pixiu index health scrapes.",
+			"Number of errors while parsing JSON.",
+		),
+
+		metrics:    indexHealthMetrics,
+		statusDesc: indexHealthStatus,
+	}
+}
+
+// Describe set Prometheus metrics descriptions.
+func (c *IndexHealth) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.Desc
+	}
+	ch <- c.statusDesc.Desc
+
+	ch <- c.bookkeeping.Up.Desc()
+	ch <- c.bookkeeping.TotalScrapes.Desc()
+	ch <- c.bookkeeping.JSONParseFailures.Desc()
+}
+
+// Descriptions returns a MetricDescription for every metric this collector
+// can emit, read straight off this instance's own fields.
+func (c *IndexHealth) Descriptions() []MetricDescription {
+	out := make([]MetricDescription, 0, len(c.metrics)+4)
+	for _, metric := range c.metrics {
+		out = append(out, metric.description(gaugeType))
+	}
+	out = append(out, c.statusDesc.description(gaugeType))
+	out = append(out, c.bookkeeping.Descriptions()...)
+	return out
+}
+
+// Collect collects IndexHealth metrics.
+func (c *IndexHealth) Collect(ch chan<- prometheus.Metric) {
+	c.bookkeeping.TotalScrapes.Inc()
+
+	defer func() {
+		ch <- c.bookkeeping.Up
+		ch <- c.bookkeeping.TotalScrapes
+		ch <- c.bookkeeping.JSONParseFailures
+	}()
+
+	resp, err := c.fetchAndDecodeIndicesHealth()
+	if err != nil {
+		c.bookkeeping.Up.Set(0)
+		_ = c.logger.Output(
+			2,
+			"msg:"+"failed to fetch and decode index health"+"err:"+err.Error(),
+		)
+		return
+	}
+
+	c.bookkeeping.Up.Set(1)
+
+	for index, indexHealth := range resp.Indices {
+		for _, metric := range c.metrics {
+			ch <- prometheus.MustNewConstMetric(
+				metric.Desc,
+				metric.Type,
+				metric.Value(indexHealth),
+				resp.ClusterName, c.target, index,
+			)
+		}
+
+		for _, color := range colors {
+			value := 0.0
+			if indexHealth.Status == color {
+				value = 1.0
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.statusDesc.Desc,
+				prometheus.GaugeValue,
+				value,
+				resp.ClusterName, c.target, index, color,
+			)
+		}
+	}
+}
+
+func (c *IndexHealth) fetchAndDecodeIndicesHealth() (clusterIndicesHealthResponse, error) {
+	var resp clusterIndicesHealthResponse
+
+	u := *c.url
+	u.Path = path.Join(u.Path, "/_cluster/health")
+	q := u.Query()
+	q.Set("level", "indices")
+	u.RawQuery = q.Encode()
+
+	c.limiter.acquire()
+	defer c.limiter.release()
+
+	res, err := c.client.Get(u.String())
+	if err != nil {
+		return resp, fmt.Errorf("failed to get index health from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = c.logger.Output(
+				2,
+				"msg:"+"failed to close http.Client"+"err:"+err.Error(),
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	bts, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		c.bookkeeping.JSONParseFailures.Inc()
+		return resp, err
+	}
+
+	if err := json.Unmarshal(bts, &resp); err != nil {
+		c.bookkeeping.JSONParseFailures.Inc()
+		return resp, err
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,56 @@
+package collector
+
+import "github.com/pixiu/config"
+
+// InterpretedStatus is a 4-value severity classification derived from a
+// cluster's raw 3-color status plus a configurable set of WARN signals, so
+// alert rules can distinguish "page me now" from "look at it in the
+// morning".
+type InterpretedStatus float64
+
+const (
+	StatusOK           InterpretedStatus = 0
+	StatusSoftWarn     InterpretedStatus = 1
+	StatusCriticalWarn InterpretedStatus = 2
+	StatusErr          InterpretedStatus = 3
+)
+
+// Interpreter classifies a cluster health response into an InterpretedStatus.
+// Operators can supply their own implementation to NewClusterHealth without
+// forking the collector.
+type Interpreter interface {
+	Interpret(ClusterHealthResponse) InterpretedStatus
+}
+
+// thresholdInterpreter is the default Interpreter. It passes "green" and
+// "red" straight through, and splits "yellow" into soft_warn or
+// critical_warn depending on whether any of Thresholds is exceeded.
+type thresholdInterpreter struct {
+	thresholds config.InterpreterThresholds
+}
+
+// NewThresholdInterpreter returns the default Interpreter, parameterised by
+// the given thresholds.
+func NewThresholdInterpreter(thresholds config.InterpreterThresholds) Interpreter {
+	return &thresholdInterpreter{thresholds: thresholds}
+}
+
+func (i *thresholdInterpreter) Interpret(chr ClusterHealthResponse) InterpretedStatus {
+	switch chr.Status {
+	case "green":
+		return StatusOK
+	case "yellow":
+		if chr.UnassignedShards > i.thresholds.UnassignedShards ||
+			chr.NumberOfPendingTasks > i.thresholds.PendingTasks ||
+			chr.InitializingShards > i.thresholds.InitializingShards ||
+			chr.TaskMaxWaitingInQueueMillis > i.thresholds.TaskMaxWaitingInQueueMillis ||
+			chr.ActiveShardsPercentAsNumber < i.thresholds.ActiveShardsPercentBelow {
+			return StatusCriticalWarn
+		}
+		return StatusSoftWarn
+	case "red":
+		return StatusErr
+	default:
+		return StatusErr
+	}
+}
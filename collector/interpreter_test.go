@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/pixiu/config"
+)
+
+func TestThresholdInterpreterInterpret(t *testing.T) {
+	thresholds := config.InterpreterThresholds{
+		UnassignedShards:            1,
+		PendingTasks:                20,
+		InitializingShards:          5,
+		TaskMaxWaitingInQueueMillis: 30000,
+		ActiveShardsPercentBelow:    95,
+	}
+	interpreter := NewThresholdInterpreter(thresholds)
+
+	tests := []struct {
+		name string
+		chr  ClusterHealthResponse
+		want InterpretedStatus
+	}{
+		{
+			name: "green is always ok",
+			chr:  ClusterHealthResponse{Status: "green"},
+			want: StatusOK,
+		},
+		{
+			name: "red is always err",
+			chr:  ClusterHealthResponse{Status: "red"},
+			want: StatusErr,
+		},
+		{
+			name: "unrecognized status is err",
+			chr:  ClusterHealthResponse{Status: "purple"},
+			want: StatusErr,
+		},
+		{
+			name: "yellow within every threshold is a soft warn",
+			chr:  ClusterHealthResponse{Status: "yellow", ActiveShardsPercentAsNumber: 96},
+			want: StatusSoftWarn,
+		},
+		{
+			name: "yellow exceeding unassigned_shards is a critical warn",
+			chr:  ClusterHealthResponse{Status: "yellow", UnassignedShards: 2, ActiveShardsPercentAsNumber: 96},
+			want: StatusCriticalWarn,
+		},
+		{
+			name: "yellow below active_shards_percent_below is a critical warn",
+			chr:  ClusterHealthResponse{Status: "yellow", ActiveShardsPercentAsNumber: 94},
+			want: StatusCriticalWarn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpreter.Interpret(tt.chr); got != tt.want {
+				t.Errorf("Interpret(%+v) = %v, want %v", tt.chr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestThresholdInterpreterDefaultsReachSoftWarn guards against the
+// ActiveShardsPercentBelow: 100 regression that made soft_warn unreachable
+// under config.DefaultInterpreterThresholds: with the threshold at 100,
+// any cluster below 100% active shards (i.e. any real "yellow") was
+// immediately a critical warn.
+func TestThresholdInterpreterDefaultsReachSoftWarn(t *testing.T) {
+	interpreter := NewThresholdInterpreter(config.DefaultInterpreterThresholds)
+
+	chr := ClusterHealthResponse{Status: "yellow", ActiveShardsPercentAsNumber: 96}
+	if got := interpreter.Interpret(chr); got != StatusSoftWarn {
+		t.Errorf("Interpret(%+v) = %v, want %v (soft_warn must be reachable under default thresholds)", chr, got, StatusSoftWarn)
+	}
+}
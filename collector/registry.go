@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"github.com/pixiu/global"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	gaugeType   = "gauge"
+	counterType = "counter"
+)
+
+// MetricDescription is a machine-readable summary of a single Prometheus
+// metric a collector can emit: enough for downstream tooling (Grafana
+// dashboard generators, alert-rule linters, docs) to know what exists
+// without scraping a live instance.
+type MetricDescription struct {
+	Name        string            `json:"name"`
+	Help        string            `json:"help"`
+	Type        string            `json:"type"`
+	Labels      []string          `json:"labels,omitempty"`
+	ConstLabels map[string]string `json:"constLabels,omitempty"`
+}
+
+// describer is implemented by every collector this package builds, plus any
+// sibling package built on top of it (e.g. alerts.Collector): it reports the
+// shape of every metric a real instance emits, read off that instance's own
+// fields rather than a separately hand-maintained list, so a metric rename
+// can't drift out of sync with what Collect/Describe actually produce.
+type describer interface {
+	Descriptions() []MetricDescription
+}
+
+// Descriptors walks every given collector and returns a stable,
+// machine-readable manifest of every metric it can emit. It requires no
+// live upstream, so it's safe to run in CI (see the dump_metrics-tagged
+// test) to catch accidental metric renames.
+func Descriptors(collectors ...describer) []MetricDescription {
+	var out []MetricDescription
+	for _, c := range collectors {
+		out = append(out, c.Descriptions()...)
+	}
+	return out
+}
+
+// namedDesc pairs a *prometheus.Desc with the fqName/help/labels that went
+// into building it, so callers can report a MetricDescription for it
+// without re-typing that information a second time.
+type namedDesc struct {
+	FQName string
+	Help   string
+	Labels []string
+	Desc   *prometheus.Desc
+}
+
+// newNamedDesc builds a *prometheus.Desc the way prometheus.NewDesc does,
+// keeping the fqName/help/labels that produced it alongside it so Collect
+// and Descriptions() can't drift apart.
+func newNamedDesc(subsystem, name, help string, labels []string) namedDesc {
+	fqName := prometheus.BuildFQName(global.Namespace, subsystem, name)
+	return namedDesc{
+		FQName: fqName,
+		Help:   help,
+		Labels: labels,
+		Desc:   prometheus.NewDesc(fqName, help, labels, nil),
+	}
+}
+
+// description reports d's shape as a MetricDescription, tagged with
+// metricType (namedDesc itself doesn't know gauge vs. counter).
+func (d namedDesc) description(metricType string) MetricDescription {
+	return MetricDescription{
+		Name:   d.FQName,
+		Help:   d.Help,
+		Type:   metricType,
+		Labels: d.Labels,
+	}
+}
+
+// bookkeepingMetrics are the up/total_scrapes/json_parse_failures trio every
+// *Health collector exposes, each tagged with the target it was built for so
+// two targets registered into the same registry don't collide.
+type bookkeepingMetrics struct {
+	Up                prometheus.Gauge
+	TotalScrapes      prometheus.Counter
+	JSONParseFailures prometheus.Counter
+
+	descriptions []MetricDescription
+}
+
+// newBookkeepingMetrics builds the up/total_scrapes/json_parse_failures trio
+// for subsystem, const-labelled with target. Each help string is written
+// once, here, and used both for the real Gauge/Counter and for the
+// MetricDescription Descriptions() reports, so the two can't drift apart.
+func newBookkeepingMetrics(subsystem, target, upHelp, totalScrapesHelp, jsonParseFailuresHelp string) bookkeepingMetrics {
+	constLabels := prometheus.Labels{"target": target}
+
+	upName := prometheus.BuildFQName(global.Namespace, subsystem, "up")
+	totalScrapesName := prometheus.BuildFQName(global.Namespace, subsystem, "total_scrapes")
+	jsonParseFailuresName := prometheus.BuildFQName(global.Namespace, subsystem, "json_parse_failures")
+
+	return bookkeepingMetrics{
+		Up: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        upName,
+			Help:        upHelp,
+			ConstLabels: constLabels,
+		}),
+		TotalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        totalScrapesName,
+			Help:        totalScrapesHelp,
+			ConstLabels: constLabels,
+		}),
+		JSONParseFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        jsonParseFailuresName,
+			Help:        jsonParseFailuresHelp,
+			ConstLabels: constLabels,
+		}),
+		descriptions: []MetricDescription{
+			{Name: upName, Help: upHelp, Type: gaugeType, ConstLabels: map[string]string{"target": target}},
+			{Name: totalScrapesName, Help: totalScrapesHelp, Type: counterType, ConstLabels: map[string]string{"target": target}},
+			{Name: jsonParseFailuresName, Help: jsonParseFailuresHelp, Type: counterType, ConstLabels: map[string]string{"target": target}},
+		},
+	}
+}
+
+// Descriptions returns the MetricDescription for each of the trio, in the
+// same up/total_scrapes/json_parse_failures order Describe sends them.
+func (b bookkeepingMetrics) Descriptions() []MetricDescription {
+	return b.descriptions
+}
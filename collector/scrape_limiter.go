@@ -0,0 +1,26 @@
+package collector
+
+// scrapeLimiter bounds how many upstream HTTP scrapes are in flight at once
+// across every collector sharing it, so one slow upstream cluster can't
+// starve the others of connections. NewHandler builds one fresh per
+// incoming /metrics request and hands it to every collector built for that
+// request; a nil scrapeLimiter applies no bound, which is convenient for
+// tests and other callers that don't need throttling.
+type scrapeLimiter chan struct{}
+
+// newScrapeLimiter returns a scrapeLimiter allowing up to n scrapes at once.
+func newScrapeLimiter(n int) scrapeLimiter {
+	return make(scrapeLimiter, n)
+}
+
+func (l scrapeLimiter) acquire() {
+	if l != nil {
+		l <- struct{}{}
+	}
+}
+
+func (l scrapeLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
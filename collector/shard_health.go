@@ -0,0 +1,244 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const shardHealthSubsystem = "shard_health_subsystem"
+
+var defaultShardHealthLabels = []string{"cluster", "target", "index", "shard", "primary"}
+
+type shardHealthResponse struct {
+	Status             string `json:"status"`
+	PrimaryActive      bool   `json:"primary_active"`
+	ActiveShards       int    `json:"active_shards"`
+	RelocatingShards   int    `json:"relocating_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
+}
+
+type indexShardsHealthResponse struct {
+	Shards map[string]shardHealthResponse `json:"shards"`
+}
+
+type clusterShardsHealthResponse struct {
+	ClusterName string                               `json:"cluster_name"`
+	Indices     map[string]indexShardsHealthResponse `json:"indices"`
+}
+
+type shardHealthMetric struct {
+	Type  prometheus.ValueType
+	Value func(shardHealth shardHealthResponse) float64
+	namedDesc
+}
+
+// newShardHealthMetric builds a gauge-valued shardHealthMetric backed by a
+// namedDesc.
+func newShardHealthMetric(name, help string, value func(shardHealthResponse) float64) *shardHealthMetric {
+	return &shardHealthMetric{
+		Type:      prometheus.GaugeValue,
+		Value:     value,
+		namedDesc: newNamedDesc(shardHealthSubsystem, name, help, defaultShardHealthLabels),
+	}
+}
+
+// shardHealthMetrics and shardHealthStatus are built once at package-init
+// time and shared by every ShardHealth instance, mirroring
+// clusterHealthMetrics.
+var (
+	shardHealthMetrics = []*shardHealthMetric{
+		newShardHealthMetric("active_shards",
+			"Aggregate total of active copies of this shard.",
+			func(shardHealth shardHealthResponse) float64 {
+				return float64(shardHealth.ActiveShards)
+			}),
+		newShardHealthMetric("relocating_shards",
+			"The number of copies of this shard currently moving from one node to another node.",
+			func(shardHealth shardHealthResponse) float64 {
+				return float64(shardHealth.RelocatingShards)
+			}),
+		newShardHealthMetric("initializing_shards",
+			"Count of copies of this shard that are being freshly created.",
+			func(shardHealth shardHealthResponse) float64 {
+				return float64(shardHealth.InitializingShards)
+			}),
+		newShardHealthMetric("unassigned_shards",
+			"The number of copies of this shard that exist in the cluster state, but cannot be found in the cluster itself.",
+			func(shardHealth shardHealthResponse) float64 {
+				return float64(shardHealth.UnassignedShards)
+			}),
+	}
+
+	shardHealthStatusLabels = append(append([]string{}, defaultShardHealthLabels...), "color")
+
+	shardHealthStatus = newNamedDesc(shardHealthSubsystem, "status",
+		"Whether this shard's primary and replica copies are allocated.", shardHealthStatusLabels)
+)
+
+// ShardHealth collects per-shard health by calling
+// /_cluster/health?level=shards, the finest-grained view node_exporter-style
+// consumers can ask for.
+type ShardHealth struct {
+	logger  log.Logger
+	client  *http.Client
+	url     *url.URL
+	target  string
+	limiter scrapeLimiter
+
+	bookkeeping bookkeepingMetrics
+
+	metrics    []*shardHealthMetric
+	statusDesc namedDesc
+}
+
+// NewShardHealth builds a ShardHealth collector for a single upstream
+// cluster. limiter bounds concurrent upstream HTTP scrapes across every
+// collector sharing it; a nil limiter applies no bound.
+func NewShardHealth(logger log.Logger, client *http.Client, url *url.URL, target string, limiter scrapeLimiter) *ShardHealth {
+	return &ShardHealth{
+		logger:  logger,
+		client:  client,
+		url:     url,
+		target:  target,
+		limiter: limiter,
+
+		bookkeeping: newBookkeepingMetrics(shardHealthSubsystem, target,
+			"Was the last scrape of the Pixiu shard health successful.",
+			"Current total Pixiu shard health scrapes.",
+			"Number of errors while parsing JSON.",
+		),
+
+		metrics:    shardHealthMetrics,
+		statusDesc: shardHealthStatus,
+	}
+}
+
+// Describe set Prometheus metrics descriptions.
+func (c *ShardHealth) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.Desc
+	}
+	ch <- c.statusDesc.Desc
+
+	ch <- c.bookkeeping.Up.Desc()
+	ch <- c.bookkeeping.TotalScrapes.Desc()
+	ch <- c.bookkeeping.JSONParseFailures.Desc()
+}
+
+// Descriptions returns a MetricDescription for every metric this collector
+// can emit, read straight off this instance's own fields.
+func (c *ShardHealth) Descriptions() []MetricDescription {
+	out := make([]MetricDescription, 0, len(c.metrics)+4)
+	for _, metric := range c.metrics {
+		out = append(out, metric.description(gaugeType))
+	}
+	out = append(out, c.statusDesc.description(gaugeType))
+	out = append(out, c.bookkeeping.Descriptions()...)
+	return out
+}
+
+// Collect collects ShardHealth metrics.
+func (c *ShardHealth) Collect(ch chan<- prometheus.Metric) {
+	c.bookkeeping.TotalScrapes.Inc()
+
+	defer func() {
+		ch <- c.bookkeeping.Up
+		ch <- c.bookkeeping.TotalScrapes
+		ch <- c.bookkeeping.JSONParseFailures
+	}()
+
+	resp, err := c.fetchAndDecodeShardsHealth()
+	if err != nil {
+		c.bookkeeping.Up.Set(0)
+		_ = c.logger.Output(
+			2,
+			"msg:"+"failed to fetch and decode shard health"+"err:"+err.Error(),
+		)
+		return
+	}
+
+	c.bookkeeping.Up.Set(1)
+
+	for index, indexShards := range resp.Indices {
+		for shard, shardHealth := range indexShards.Shards {
+			primary := strconv.FormatBool(shardHealth.PrimaryActive)
+
+			for _, metric := range c.metrics {
+				ch <- prometheus.MustNewConstMetric(
+					metric.Desc,
+					metric.Type,
+					metric.Value(shardHealth),
+					resp.ClusterName, c.target, index, shard, primary,
+				)
+			}
+
+			for _, color := range colors {
+				value := 0.0
+				if shardHealth.Status == color {
+					value = 1.0
+				}
+				ch <- prometheus.MustNewConstMetric(
+					c.statusDesc.Desc,
+					prometheus.GaugeValue,
+					value,
+					resp.ClusterName, c.target, index, shard, primary, color,
+				)
+			}
+		}
+	}
+}
+
+func (c *ShardHealth) fetchAndDecodeShardsHealth() (clusterShardsHealthResponse, error) {
+	var resp clusterShardsHealthResponse
+
+	u := *c.url
+	u.Path = path.Join(u.Path, "/_cluster/health")
+	q := u.Query()
+	q.Set("level", "shards")
+	u.RawQuery = q.Encode()
+
+	c.limiter.acquire()
+	defer c.limiter.release()
+
+	res, err := c.client.Get(u.String())
+	if err != nil {
+		return resp, fmt.Errorf("failed to get shard health from %s://%s:%s%s: %s",
+			u.Scheme, u.Hostname(), u.Port(), u.Path, err)
+	}
+
+	defer func() {
+		err = res.Body.Close()
+		if err != nil {
+			_ = c.logger.Output(
+				2,
+				"msg:"+"failed to close http.Client"+"err:"+err.Error(),
+			)
+		}
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("HTTP Request failed with code %d", res.StatusCode)
+	}
+
+	bts, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		c.bookkeeping.JSONParseFailures.Inc()
+		return resp, err
+	}
+
+	if err := json.Unmarshal(bts, &resp); err != nil {
+		c.bookkeeping.JSONParseFailures.Inc()
+		return resp, err
+	}
+
+	return resp, nil
+}
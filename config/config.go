@@ -0,0 +1,137 @@
+// Package config loads the Pixiu targets configuration: the set of
+// upstream clusters a single Pixiu instance scrapes.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BasicAuth holds optional per-target HTTP basic-auth credentials.
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// TLSConfig holds optional per-target TLS settings for reaching a secured
+// upstream cluster.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Target describes a single upstream cluster Pixiu should scrape.
+type Target struct {
+	Name            string     `yaml:"name"`
+	URL             string     `yaml:"url"`
+	BasicAuth       *BasicAuth `yaml:"basic_auth,omitempty"`
+	TLSConfig       *TLSConfig `yaml:"tls_config,omitempty"`
+	BearerTokenFile string     `yaml:"bearer_token_file,omitempty"`
+
+	// ParsedURL is populated by Load and is what collectors use to reach
+	// the target; URL is kept around only for (re-)marshalling.
+	ParsedURL *url.URL `yaml:"-"`
+}
+
+// InterpreterThresholds configures when a "yellow" cluster health should be
+// classified as a critical WARN, rather than a soft one, by the interpreted
+// status gauge.
+type InterpreterThresholds struct {
+	UnassignedShards            int     `yaml:"unassigned_shards"`
+	PendingTasks                int     `yaml:"pending_tasks"`
+	InitializingShards          int     `yaml:"initializing_shards"`
+	TaskMaxWaitingInQueueMillis int     `yaml:"task_max_waiting_in_queue_millis"`
+	ActiveShardsPercentBelow    float64 `yaml:"active_shards_percent_below"`
+}
+
+// DefaultInterpreterThresholds are applied when a config omits the
+// interpreter_thresholds section entirely.
+var DefaultInterpreterThresholds = InterpreterThresholds{
+	UnassignedShards:            0,
+	PendingTasks:                20,
+	InitializingShards:          5,
+	TaskMaxWaitingInQueueMillis: 30000,
+	ActiveShardsPercentBelow:    95,
+}
+
+// DefaultAlertsPollInterval is used when a config omits alerts.poll_interval.
+const DefaultAlertsPollInterval = 30 * time.Second
+
+// AlertsConfig configures the optional /alerts subsystem, which surfaces a
+// Prometheus server's firing/pending alerts through Pixiu. The subsystem is
+// disabled when PrometheusURL is empty.
+type AlertsConfig struct {
+	PrometheusURL string   `yaml:"prometheus_url"`
+	PollInterval  string   `yaml:"poll_interval"`
+	LabelMatchers []string `yaml:"label_matchers,omitempty"`
+
+	// ParsedPollInterval is populated by Load and is what the alerts
+	// package uses; PollInterval is kept around only for (re-)marshalling.
+	ParsedPollInterval time.Duration `yaml:"-"`
+}
+
+// Config is the top-level Pixiu configuration file.
+type Config struct {
+	Targets []Target `yaml:"targets"`
+
+	// Interpreter is a pointer so Load can tell "operator omitted
+	// interpreter_thresholds entirely" (nil, gets DefaultInterpreterThresholds)
+	// apart from "operator explicitly configured all-zero thresholds" (non-nil
+	// zero value, a legitimate stricter config that must not be clobbered).
+	Interpreter *InterpreterThresholds `yaml:"interpreter_thresholds"`
+	Alerts      AlertsConfig           `yaml:"alerts"`
+}
+
+// Load reads and parses a Pixiu targets config file from path.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if cfg.Interpreter == nil {
+		defaults := DefaultInterpreterThresholds
+		cfg.Interpreter = &defaults
+	}
+
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if t.Name == "" {
+			return nil, fmt.Errorf("target %d: name is required", i)
+		}
+
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: invalid url %q: %w", t.Name, t.URL, err)
+		}
+		t.ParsedURL = u
+
+		if t.BasicAuth != nil && t.BearerTokenFile != "" {
+			return nil, fmt.Errorf("target %q: basic_auth and bearer_token_file are mutually exclusive", t.Name)
+		}
+	}
+
+	if cfg.Alerts.PrometheusURL != "" {
+		cfg.Alerts.ParsedPollInterval = DefaultAlertsPollInterval
+		if cfg.Alerts.PollInterval != "" {
+			d, err := time.ParseDuration(cfg.Alerts.PollInterval)
+			if err != nil {
+				return nil, fmt.Errorf("alerts: invalid poll_interval %q: %w", cfg.Alerts.PollInterval, err)
+			}
+			cfg.Alerts.ParsedPollInterval = d
+		}
+	}
+
+	return &cfg, nil
+}
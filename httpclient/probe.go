@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pixiu/global"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const probeSubsystem = "probe"
+
+// ProbeMetricDescription describes a single metric a Prober can emit. It
+// mirrors collector.MetricDescription's shape so a caller that already
+// depends on both packages (e.g. the dump-metrics manifest) can fold it in
+// without httpclient importing collector and creating an import cycle
+// (collector already imports httpclient for Prober itself).
+type ProbeMetricDescription struct {
+	Name        string
+	Help        string
+	ConstLabels map[string]string
+}
+
+const (
+	certExpiryName   = "ssl_earliest_cert_expiry_seconds"
+	certExpiryHelp   = "Number of seconds until the upstream's earliest TLS certificate expires."
+	handshakeErrName = "last_handshake_error"
+	handshakeErrHelp = "Whether the last TLS handshake with the upstream failed (1) or not (0)."
+)
+
+// Prober wraps a RoundTripper and records transport health: how many
+// seconds remain until the upstream's earliest TLS certificate expires, and
+// whether the last handshake failed. It implements prometheus.Collector so
+// it can be scraped like any other collector, and is itself an
+// http.RoundTripper so it can sit in front of the transport it's probing.
+type Prober struct {
+	next   http.RoundTripper
+	target string
+
+	certExpiry   *prometheus.Desc
+	handshakeErr *prometheus.Desc
+
+	mu                sync.Mutex
+	certExpirySeconds float64
+	lastHandshakeErr  float64
+}
+
+// NewProber wraps next, tagging the metrics it produces with target.
+func NewProber(next http.RoundTripper, target string) *Prober {
+	return &Prober{
+		next:   next,
+		target: target,
+		certExpiry: prometheus.NewDesc(
+			prometheus.BuildFQName(global.Namespace, probeSubsystem, certExpiryName),
+			certExpiryHelp,
+			nil, prometheus.Labels{"target": target},
+		),
+		handshakeErr: prometheus.NewDesc(
+			prometheus.BuildFQName(global.Namespace, probeSubsystem, handshakeErrName),
+			handshakeErrHelp,
+			nil, prometheus.Labels{"target": target},
+		),
+	}
+}
+
+// RoundTrip delegates to the wrapped transport and records the resulting
+// TLS health before returning.
+func (p *Prober) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := p.next.RoundTrip(req)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		if isTLSHandshakeError(err) {
+			p.lastHandshakeErr = 1
+		}
+		return resp, err
+	}
+	p.lastHandshakeErr = 0
+
+	if resp.TLS != nil {
+		if expiry := earliestExpiry(resp.TLS.PeerCertificates); !expiry.IsZero() {
+			p.certExpirySeconds = time.Until(expiry).Seconds()
+		}
+	}
+
+	return resp, nil
+}
+
+// Describe implements prometheus.Collector.
+func (p *Prober) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.certExpiry
+	ch <- p.handshakeErr
+}
+
+// Descriptions returns a ProbeMetricDescription for every metric this
+// Prober can emit, so it can take part in a full metrics manifest.
+func (p *Prober) Descriptions() []ProbeMetricDescription {
+	constLabels := map[string]string{"target": p.target}
+	return []ProbeMetricDescription{
+		{
+			Name:        prometheus.BuildFQName(global.Namespace, probeSubsystem, certExpiryName),
+			Help:        certExpiryHelp,
+			ConstLabels: constLabels,
+		},
+		{
+			Name:        prometheus.BuildFQName(global.Namespace, probeSubsystem, handshakeErrName),
+			Help:        handshakeErrHelp,
+			ConstLabels: constLabels,
+		},
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (p *Prober) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	certExpirySeconds, lastHandshakeErr := p.certExpirySeconds, p.lastHandshakeErr
+	p.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(p.certExpiry, prometheus.GaugeValue, certExpirySeconds)
+	ch <- prometheus.MustNewConstMetric(p.handshakeErr, prometheus.GaugeValue, lastHandshakeErr)
+}
+
+// isTLSHandshakeError reports whether err stems from the TLS handshake
+// itself (certificate verification, hostname mismatch, protocol alerts)
+// rather than an unrelated transport failure like a DNS lookup, connection
+// refusal or context timeout, so last_handshake_error only reflects what its
+// name promises.
+func isTLSHandshakeError(err error) bool {
+	var certVerifyErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+
+	return errors.As(err, &certVerifyErr) ||
+		errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalidErr) ||
+		errors.As(err, &recordHeaderErr)
+}
+
+func earliestExpiry(certs []*x509.Certificate) time.Time {
+	var earliest time.Time
+	for _, cert := range certs {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return earliest
+}
+
+// NewProbeHandler serves the transport health of every given Prober on its
+// own Prometheus exposition, independent of /metrics, so certificate
+// rotation issues are visible even when the upstream scrape itself is
+// failing.
+func NewProbeHandler(probers ...*Prober) http.HandlerFunc {
+	registry := prometheus.NewRegistry()
+	for _, p := range probers {
+		registry.MustRegister(p)
+	}
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP
+}
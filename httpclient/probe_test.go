@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIsTLSHandshakeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "unknown authority is a handshake error",
+			err:  x509.UnknownAuthorityError{},
+			want: true,
+		},
+		{
+			name: "hostname mismatch is a handshake error",
+			err:  x509.HostnameError{},
+			want: true,
+		},
+		{
+			name: "certificate invalid is a handshake error",
+			err:  x509.CertificateInvalidError{},
+			want: true,
+		},
+		{
+			name: "tls record header error is a handshake error",
+			err:  tls.RecordHeaderError{},
+			want: true,
+		},
+		{
+			name: "wrapped handshake error is still a handshake error",
+			err:  fmt.Errorf("dial: %w", x509.UnknownAuthorityError{}),
+			want: true,
+		},
+		{
+			name: "dns lookup failure is not a handshake error",
+			err:  &net.DNSError{Err: "no such host", Name: "example.invalid"},
+			want: false,
+		},
+		{
+			name: "connection refused is not a handshake error",
+			err:  errors.New("dial tcp: connection refused"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTLSHandshakeError(tt.err); got != tt.want {
+				t.Errorf("isTLSHandshakeError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
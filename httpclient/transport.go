@@ -0,0 +1,165 @@
+// Package httpclient builds the http.RoundTripper Pixiu uses to reach a
+// (possibly secured) upstream cluster: basic auth, a periodically-reloaded
+// bearer token, and/or mTLS, all without the caller having to fork the
+// exporter to add their own auth scheme.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pixiu/config"
+	"github.com/pixiu/global"
+)
+
+// DefaultBearerTokenReloadInterval is used when a caller doesn't need a
+// different reload cadence for rotated bearer tokens.
+const DefaultBearerTokenReloadInterval = 5 * time.Minute
+
+// New builds the http.RoundTripper for target, layering basic auth, a
+// reloaded bearer token and/or mTLS on top of http.DefaultTransport as
+// target's configuration requires, and stamping every outgoing request with
+// a Pixiu User-Agent.
+func New(target config.Target, bearerTokenReloadInterval time.Duration) (http.RoundTripper, error) {
+	var rt http.RoundTripper = http.DefaultTransport
+
+	if target.TLSConfig != nil {
+		tlsConfig, err := buildTLSConfig(target.TLSConfig)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", target.Name, err)
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		rt = transport
+	}
+
+	if target.BasicAuth != nil {
+		rt = &basicAuthTransport{
+			next:     rt,
+			username: target.BasicAuth.Username,
+			password: target.BasicAuth.Password,
+		}
+	}
+
+	if target.BearerTokenFile != "" {
+		bearerRT, err := newBearerTokenTransport(rt, target.BearerTokenFile, bearerTokenReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", target.Name, err)
+		}
+		rt = bearerRT
+	}
+
+	return &userAgentTransport{next: rt, userAgent: "Pixiu/" + global.Version}, nil
+}
+
+func buildTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file %s: %w", cfg.CAFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse ca file %s: no valid certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert/key %s/%s: %w", cfg.CertFile, cfg.KeyFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// userAgentTransport stamps every outgoing request with a Pixiu User-Agent.
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
+
+// basicAuthTransport attaches HTTP basic-auth credentials to every request.
+type basicAuthTransport struct {
+	next     http.RoundTripper
+	username string
+	password string
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password)
+	return t.next.RoundTrip(req)
+}
+
+// bearerTokenTransport attaches a bearer token read from a file, reloading
+// it on an interval so a rotated token takes effect without a restart.
+type bearerTokenTransport struct {
+	next http.RoundTripper
+
+	mu    sync.RWMutex
+	token string
+}
+
+func newBearerTokenTransport(next http.RoundTripper, path string, reloadInterval time.Duration) (*bearerTokenTransport, error) {
+	t := &bearerTokenTransport{next: next}
+	if err := t.reload(path); err != nil {
+		return nil, err
+	}
+
+	if reloadInterval > 0 {
+		go t.reloadLoop(path, reloadInterval)
+	}
+
+	return t, nil
+}
+
+func (t *bearerTokenTransport) reload(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bearer token file %s: %w", path, err)
+	}
+
+	t.mu.Lock()
+	t.token = strings.TrimSpace(string(b))
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *bearerTokenTransport) reloadLoop(path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_ = t.reload(path)
+	}
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	token := t.token
+	t.mu.RUnlock()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pixiu/config"
+	"github.com/pixiu/global"
+)
+
+// roundTripFunc lets a plain function satisfy http.RoundTripper, so tests
+// can inspect the request a transport wrapper hands to "next" without
+// spinning up a real server.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func httpGetRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://example.invalid", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest() error = %v", err)
+	}
+	return req
+}
+
+func writeTokenFile(t *testing.T, token string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	return path
+}
+
+func TestBasicAuthTransportRoundTrip(t *testing.T) {
+	var gotUser, gotPass string
+	var gotOK bool
+
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotUser, gotPass, gotOK = req.BasicAuth()
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	rt := &basicAuthTransport{next: next, username: "alice", password: "hunter2"}
+	if _, err := rt.RoundTrip(httpGetRequest(t)); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+
+	if !gotOK || gotUser != "alice" || gotPass != "hunter2" {
+		t.Errorf("RoundTrip() set BasicAuth = (%q, %q, %v), want (alice, hunter2, true)", gotUser, gotPass, gotOK)
+	}
+}
+
+func TestBearerTokenTransportRoundTrip(t *testing.T) {
+	path := writeTokenFile(t, "s3cr3t")
+
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if got, want := req.Header.Get("Authorization"), "Bearer s3cr3t"; got != want {
+			t.Errorf("Authorization header = %q, want %q", got, want)
+		}
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	rt, err := newBearerTokenTransport(next, path, 0)
+	if err != nil {
+		t.Fatalf("newBearerTokenTransport() error = %v", err)
+	}
+
+	if _, err := rt.RoundTrip(httpGetRequest(t)); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+}
+
+func TestUserAgentTransportRoundTrip(t *testing.T) {
+	var gotUA string
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotUA = req.Header.Get("User-Agent")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	rt := &userAgentTransport{next: next, userAgent: "Pixiu/test"}
+	if _, err := rt.RoundTrip(httpGetRequest(t)); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+
+	if gotUA != "Pixiu/test" {
+		t.Errorf("User-Agent header = %q, want %q", gotUA, "Pixiu/test")
+	}
+}
+
+func TestNewWiresBasicAuthAndUserAgent(t *testing.T) {
+	var gotUser, gotPass, gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotPass, _ = r.BasicAuth()
+		gotUA = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	target := config.Target{
+		Name:      "basic-only",
+		BasicAuth: &config.BasicAuth{Username: "alice", Password: "hunter2"},
+	}
+
+	rt, err := New(target, 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	client := &http.Client{Transport: rt}
+	if _, err := client.Get(srv.URL); err != nil {
+		t.Fatalf("client.Get() error = %v", err)
+	}
+
+	if gotUser != "alice" || gotPass != "hunter2" {
+		t.Errorf("upstream saw BasicAuth = (%q, %q), want (alice, hunter2)", gotUser, gotPass)
+	}
+	if gotUA != "Pixiu/"+global.Version {
+		t.Errorf("upstream saw User-Agent = %q, want %q", gotUA, "Pixiu/"+global.Version)
+	}
+}
+
+func TestNewWiresBearerToken(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	path := writeTokenFile(t, "s3cr3t")
+	target := config.Target{Name: "bearer-only", BearerTokenFile: path}
+
+	rt, err := New(target, 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	client := &http.Client{Transport: rt}
+	if _, err := client.Get(srv.URL); err != nil {
+		t.Fatalf("client.Get() error = %v", err)
+	}
+
+	if want := "Bearer s3cr3t"; gotAuth != want {
+		t.Errorf("upstream saw Authorization = %q, want %q", gotAuth, want)
+	}
+}
+
+func TestBuildTLSConfigInsecureSkipVerify(t *testing.T) {
+	tlsConfig, err := buildTLSConfig(&config.TLSConfig{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("buildTLSConfig() error = %v", err)
+	}
+
+	if !tlsConfig.InsecureSkipVerify {
+		t.Error("buildTLSConfig() InsecureSkipVerify = false, want true")
+	}
+}
+
+func TestBuildTLSConfigMissingCAFile(t *testing.T) {
+	if _, err := buildTLSConfig(&config.TLSConfig{CAFile: "/does/not/exist.pem"}); err == nil {
+		t.Error("buildTLSConfig() error = nil, want error for missing ca_file")
+	}
+}